@@ -0,0 +1,231 @@
+package channel
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultArchiveCacheSize = 64
+	// defaultArchiveRateLimit and defaultArchiveRateBurst bound how many
+	// archive requests a single channel/revision can make per second
+	// before getting a 429, so one noisy worker can't starve the others.
+	defaultArchiveRateLimit = 5
+	defaultArchiveRateBurst = 10
+	// defaultRateLimiterCacheSize bounds how many distinct refs' limiters
+	// are kept around at once, so requests for an unbounded number of
+	// distinct (possibly caller-controlled) refs can't grow limiters
+	// without bound.
+	defaultRateLimiterCacheSize = 1024
+)
+
+// ArchiveServer exposes resolved channel revisions of a Git source as
+// downloadable tar.gz archives over HTTP, e.g. GET /archive/<channel-or-sha>.tar.gz.
+// It lets remote CLM workers consume channel content without needing git
+// installed or SSH keys of their own.
+type ArchiveServer struct {
+	git      *Git
+	cache    *archiveCache
+	limiters *rateLimiterCache
+}
+
+// NewArchiveServer creates an ArchiveServer backed by g's mirror repository.
+// cacheSize is the number of distinct commit SHAs to keep archives for; if
+// <= 0, defaultArchiveCacheSize is used.
+func NewArchiveServer(g *Git, cacheSize int) *ArchiveServer {
+	if cacheSize <= 0 {
+		cacheSize = defaultArchiveCacheSize
+	}
+
+	return &ArchiveServer{
+		git:      g,
+		cache:    newArchiveCache(cacheSize),
+		limiters: newRateLimiterCache(defaultRateLimiterCacheSize),
+	}
+}
+
+// Handler returns the http.Handler serving channel archives.
+func (s *ArchiveServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/archive/", s.serveArchive)
+	return mux
+}
+
+// refRE bounds what serveArchive will ever pass to git as a revision. It
+// deliberately excludes a leading "-", so a path like
+// "/archive/--output=...tar.gz" can never be mistaken for a git flag by
+// rev-parse/archive further down the call chain.
+var refRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+func (s *ArchiveServer) serveArchive(w http.ResponseWriter, r *http.Request) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/archive/"), ".tar.gz")
+	if ref == "" {
+		http.Error(w, "missing channel or revision", http.StatusBadRequest)
+		return
+	}
+
+	if !refRE.MatchString(ref) {
+		http.Error(w, fmt.Sprintf("invalid channel or revision %q", ref), http.StatusBadRequest)
+		return
+	}
+
+	if !s.limiterFor(ref).Allow() {
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	sha, err := s.git.resolveRevision(r.Context(), ref)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unknown channel or revision %q", ref), http.StatusNotFound)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s"`, sha)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := s.cache.get(sha, func() ([]byte, error) {
+		return s.git.archive(r.Context(), sha)
+	})
+	if err != nil {
+		log.Errorf("failed to archive %s: %v", sha, err)
+		http.Error(w, "failed to create archive", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.Write(data)
+}
+
+// limiterFor returns the rate limiter for ref, creating one on first use.
+func (s *ArchiveServer) limiterFor(ref string) *rate.Limiter {
+	return s.limiters.get(ref)
+}
+
+// rateLimiterCache is an in-memory LRU cache of per-ref rate.Limiters,
+// bounded the same way archiveCache bounds archives: refs beyond capacity
+// evict the least recently used one. Evicting a limiter just resets that
+// ref's rate limiting state, which is an acceptable trade-off for keeping
+// memory bounded when serveArchive is called with an unbounded number of
+// distinct (possibly caller-controlled) refs.
+type rateLimiterCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type rateLimiterEntry struct {
+	ref     string
+	limiter *rate.Limiter
+}
+
+func newRateLimiterCache(capacity int) *rateLimiterCache {
+	return &rateLimiterCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the limiter for ref, creating one (and evicting the least
+// recently used entry if at capacity) on first use.
+func (c *rateLimiterCache) get(ref string) *rate.Limiter {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[ref]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*rateLimiterEntry).limiter
+	}
+
+	limiter := rate.NewLimiter(defaultArchiveRateLimit, defaultArchiveRateBurst)
+	elem := c.order.PushFront(&rateLimiterEntry{ref: ref, limiter: limiter})
+	c.items[ref] = elem
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*rateLimiterEntry).ref)
+	}
+
+	return limiter
+}
+
+// archiveCache is an in-memory LRU cache of rendered tar.gz archives keyed
+// by resolved commit SHA, so repeated fetches for the same version don't
+// re-run git-archive.
+type archiveCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type archiveCacheEntry struct {
+	sha  string
+	data []byte
+}
+
+func newArchiveCache(capacity int) *archiveCache {
+	return &archiveCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached archive for sha, populating the cache via fetch on
+// a miss.
+func (c *archiveCache) get(sha string, fetch func() ([]byte, error)) ([]byte, error) {
+	c.mutex.Lock()
+	if elem, ok := c.items[sha]; ok {
+		c.order.MoveToFront(elem)
+		data := elem.Value.(*archiveCacheEntry).data
+		c.mutex.Unlock()
+		return data, nil
+	}
+	c.mutex.Unlock()
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// another caller may have populated the entry while we were fetching.
+	if elem, ok := c.items[sha]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*archiveCacheEntry).data, nil
+	}
+
+	elem := c.order.PushFront(&archiveCacheEntry{sha: sha, data: data})
+	c.items[sha] = elem
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*archiveCacheEntry).sha)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,115 @@
+package channel
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestArchiveCacheEviction(t *testing.T) {
+	cache := newArchiveCache(2)
+	fetches := 0
+
+	fetch := func(sha string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			fetches++
+			return []byte(sha), nil
+		}
+	}
+
+	for _, sha := range []string{"aaa", "bbb"} {
+		if _, err := cache.get(sha, fetch(sha)); err != nil {
+			t.Fatalf("get(%s): %v", sha, err)
+		}
+	}
+	if fetches != 2 {
+		t.Fatalf("expected 2 fetches after priming, got %d", fetches)
+	}
+
+	// touching "aaa" again should be a cache hit and move it to the front.
+	if _, err := cache.get("aaa", fetch("aaa")); err != nil {
+		t.Fatalf("get(aaa): %v", err)
+	}
+	if fetches != 2 {
+		t.Fatalf("expected no extra fetch for a cache hit, got %d fetches", fetches)
+	}
+
+	// adding a third entry should evict "bbb" (least recently used), not "aaa".
+	if _, err := cache.get("ccc", fetch("ccc")); err != nil {
+		t.Fatalf("get(ccc): %v", err)
+	}
+	if fetches != 3 {
+		t.Fatalf("expected a fetch for a new entry, got %d fetches", fetches)
+	}
+
+	if _, err := cache.get("aaa", fetch("aaa")); err != nil {
+		t.Fatalf("get(aaa): %v", err)
+	}
+	if fetches != 3 {
+		t.Fatalf("expected aaa to still be cached, got %d fetches", fetches)
+	}
+
+	if _, err := cache.get("bbb", fetch("bbb")); err != nil {
+		t.Fatalf("get(bbb): %v", err)
+	}
+	if fetches != 4 {
+		t.Fatalf("expected bbb to have been evicted and re-fetched, got %d fetches", fetches)
+	}
+
+	if cache.order.Len() != 2 {
+		t.Fatalf("expected cache to hold exactly capacity entries, got %d", cache.order.Len())
+	}
+}
+
+func TestArchiveCacheEvictionOrderLimit(t *testing.T) {
+	cache := newArchiveCache(1)
+
+	for i := 0; i < 5; i++ {
+		sha := fmt.Sprintf("sha-%d", i)
+		if _, err := cache.get(sha, func() ([]byte, error) { return []byte(sha), nil }); err != nil {
+			t.Fatalf("get(%s): %v", sha, err)
+		}
+	}
+
+	if cache.order.Len() != 1 {
+		t.Fatalf("expected a capacity-1 cache to never hold more than 1 entry, got %d", cache.order.Len())
+	}
+}
+
+func TestRateLimiterCacheEviction(t *testing.T) {
+	cache := newRateLimiterCache(2)
+
+	a := cache.get("a")
+	cache.get("b")
+	if cache.order.Len() != 2 {
+		t.Fatalf("expected 2 entries after priming, got %d", cache.order.Len())
+	}
+
+	// touching "a" again should move it to the front, protecting it from eviction.
+	if got := cache.get("a"); got != a {
+		t.Fatalf("expected the same limiter instance for a repeated ref")
+	}
+
+	// adding a third ref should evict "b" (least recently used), not "a".
+	cache.get("c")
+	if cache.order.Len() != 2 {
+		t.Fatalf("expected cache to hold exactly capacity entries, got %d", cache.order.Len())
+	}
+	if got := cache.get("a"); got != a {
+		t.Fatalf("expected a's limiter to survive, got a different instance")
+	}
+	if got := cache.get("b"); got == a {
+		t.Fatalf("expected b to have been evicted and recreated, not reused from a")
+	}
+}
+
+func TestRateLimiterCacheBoundsUnboundedRefs(t *testing.T) {
+	cache := newRateLimiterCache(4)
+
+	for i := 0; i < 1000; i++ {
+		cache.get(fmt.Sprintf("ref-%d", i))
+	}
+
+	if cache.order.Len() != 4 {
+		t.Fatalf("expected cache to stay bounded at capacity after many distinct refs, got %d", cache.order.Len())
+	}
+}
@@ -1,7 +1,9 @@
 package channel
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
@@ -24,6 +26,10 @@ type Git struct {
 	repoDir           string
 	sshPrivateKeyFile string
 	mutex             *sync.Mutex
+	activePaths       map[string]bool
+	janitorState      JanitorState
+	mirrors           []MirrorTarget
+	mirrorStatus      map[string]MirrorPushStatus
 }
 
 // NewGit initializes a new git based ChannelSource.
@@ -46,9 +52,26 @@ func NewGit(workdir, repositoryURL, sshPrivateKeyFile string) (ConfigSource, err
 		repoDir:           path.Join(absWorkdir, repoName),
 		sshPrivateKeyFile: sshPrivateKeyFile,
 		mutex:             &sync.Mutex{},
+		activePaths:       make(map[string]bool),
+		mirrorStatus:      make(map[string]MirrorPushStatus),
 	}, nil
 }
 
+// NewConfigSource selects between the shell-based Git backend and the
+// native go-git backend. Environments that rely on git-specific tooling
+// (custom credential helpers, git-lfs, ...) can opt back into the shell
+// backend via useShellGit; everyone else gets GoGit, which doesn't
+// require a git binary on PATH. sshKnownHostsFile is only used by GoGit;
+// the shell backend verifies host keys the way the host's git/ssh config
+// already does.
+func NewConfigSource(workdir, repositoryURL, sshPrivateKeyFile, sshKnownHostsFile string, useShellGit bool) (ConfigSource, error) {
+	if useShellGit {
+		return NewGit(workdir, repositoryURL, sshPrivateKeyFile)
+	}
+
+	return NewGoGit(workdir, repositoryURL, sshPrivateKeyFile, sshKnownHostsFile)
+}
+
 var repoNameRE = regexp.MustCompile(`/?([\w-]+)(.git)?$`)
 
 // getRepoName parses the repository name given a repository URI.
@@ -60,14 +83,15 @@ func getRepoName(repoURI string) (string, error) {
 	return match[1], nil
 }
 
-// Get checks out the specified channel from the git repo.
-func (g *Git) Get(channel string) (*Config, error) {
-	repoDir, err := g.localClone(channel)
+// Get checks out the specified channel from the git repo. ctx cancels the
+// underlying git subprocesses, e.g. on shutdown.
+func (g *Git) Get(ctx context.Context, channel string) (*Config, error) {
+	repoDir, err := g.localClone(ctx, channel)
 	if err != nil {
 		return nil, err
 	}
 
-	version, err := g.currentRevision(repoDir)
+	version, err := g.currentRevision(ctx, repoDir)
 	if err != nil {
 		return nil, err
 	}
@@ -80,11 +104,18 @@ func (g *Git) Get(channel string) (*Config, error) {
 
 // Delete deletes the underlying git repository checkout specified by the
 // config Path.
-func (g *Git) Delete(config *Config) error {
+func (g *Git) Delete(ctx context.Context, config *Config) error {
+	g.mutex.Lock()
+	delete(g.activePaths, config.Path)
+	g.mutex.Unlock()
+
 	return os.RemoveAll(config.Path)
 }
 
-func (g *Git) Update() error {
+// Update refreshes the mirror repo in g.repoDir, cloning it first if it
+// doesn't exist yet. ctx cancels the underlying git subprocesses, e.g. on
+// shutdown.
+func (g *Git) Update(ctx context.Context) error {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
@@ -94,17 +125,24 @@ func (g *Git) Update() error {
 			return err
 		}
 
-		err = g.cmd("clone", "--mirror", g.repositoryURL, g.repoDir)
+		err = g.cmd(ctx, "clone", "--mirror", g.repositoryURL, g.repoDir)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = g.cmd("--git-dir", g.repoDir, "remote", "update", "--prune")
+	err = g.cmd(ctx, "--git-dir", g.repoDir, "remote", "update", "--prune")
 	if err != nil {
 		return err
 	}
 
+	// Mirror push failures are reported via MirrorStatus rather than
+	// failing Update, since the channel repo itself is already current.
+	// pushMirrors takes g.mutex itself, so it is released for the pushes.
+	g.mutex.Unlock()
+	g.pushMirrors(ctx)
+	g.mutex.Lock()
+
 	return nil
 }
 
@@ -113,26 +151,30 @@ func (g *Git) Update() error {
 // makes sure that each caller (possibly running concurrently) get it's
 // own version of the checkout, such that they can run concurrently
 // without data races.
-func (g *Git) localClone(channel string) (string, error) {
+func (g *Git) localClone(ctx context.Context, channel string) (string, error) {
 	repoDir := path.Join(g.workdir, fmt.Sprintf("%s_%s_%d", g.repoName, channel, time.Now().UTC().UnixNano()))
 
 	srcRepoUrl := fmt.Sprintf("file://%s", g.repoDir)
-	err := g.cmd("clone", srcRepoUrl, repoDir)
+	err := g.cmd(ctx, "clone", srcRepoUrl, repoDir)
 	if err != nil {
 		return "", err
 	}
 
-	err = g.cmd("-C", repoDir, "checkout", channel)
+	err = g.cmd(ctx, "-C", repoDir, "checkout", channel)
 	if err != nil {
 		return "", err
 	}
 
+	g.mutex.Lock()
+	g.activePaths[repoDir] = true
+	g.mutex.Unlock()
+
 	return repoDir, nil
 }
 
 // currentRevision returns the current revision of the repoDir.
-func (g *Git) currentRevision(repoDir string) (string, error) {
-	cmd := exec.Command("git", "-C", repoDir, "rev-parse", "HEAD")
+func (g *Git) currentRevision(ctx context.Context, repoDir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", repoDir, "rev-parse", "HEAD")
 	d, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -141,12 +183,60 @@ func (g *Git) currentRevision(repoDir string) (string, error) {
 	return strings.TrimSpace(string(d)), err
 }
 
-// cmd executes a git command with the correct environment set.
-func (g *Git) cmd(args ...string) error {
-	cmd := exec.Command("git", args...)
-	// set GIT_SSH_COMMAND with private-key file when pulling over ssh.
+// resolveRevision resolves a channel name or commit SHA to a full commit
+// SHA in the mirror repo, so callers never have to cache against a
+// mutable ref like a branch name. --verify rejects anything that isn't a
+// single resolvable object, and --end-of-options stops rev itself from being
+// interpreted as a git option, so a caller-controlled rev can't smuggle
+// flags into the invocation.
+func (g *Git) resolveRevision(ctx context.Context, rev string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", g.repoDir, "rev-parse", "--verify", "--end-of-options", rev)
+	d, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(d)), nil
+}
+
+// archive returns a tar.gz archive of the given revision, produced by
+// streaming `git archive` from the mirror repo in g.repoDir. -- stops rev
+// from being interpreted as a git option (e.g. "--output=...").
+func (g *Git) archive(ctx context.Context, rev string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", g.repoDir, "archive", "--format=tar.gz", "--", rev)
+	return cmd.Output()
+}
+
+// StartArchiveServer starts an HTTP server exposing resolved channel
+// revisions of g as downloadable tar.gz archives, so remote CLM workers
+// can fetch channel content without git or SSH keys of their own.
+func (g *Git) StartArchiveServer(addr string) (*ArchiveServer, error) {
+	server := NewArchiveServer(g, defaultArchiveCacheSize)
+
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: server.Handler(),
+	}
+
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("channel archive server stopped: %v", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// cmd executes a git command with the correct environment set. ctx
+// cancels the subprocess, e.g. on shutdown.
+func (g *Git) cmd(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// set GIT_SSH_COMMAND with private-key file when pulling over ssh. Host
+	// key verification is left to ssh's own defaults (the host's
+	// known_hosts), the same as running the equivalent git command by
+	// hand; we don't disable it.
 	if g.sshPrivateKeyFile != "" {
-		cmd.Env = []string{fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o 'StrictHostKeyChecking no'", g.sshPrivateKeyFile)}
+		cmd.Env = []string{fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s", g.sshPrivateKeyFile)}
 	}
 
 	return command.Run(log.StandardLogger(), cmd)
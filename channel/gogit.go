@@ -0,0 +1,257 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// GoGit is a channel source backed by an in-process go-git client instead
+// of shelling out to the git binary. It keeps a single bare mirror
+// repository on disk, updated by Update, and checks out each caller's
+// requested channel into its own worktree directory, the same layout Git
+// uses for localClone.
+type GoGit struct {
+	workdir           string
+	repositoryURL     string
+	repoName          string
+	repoDir           string
+	sshPrivateKeyFile string
+	sshKnownHostsFile string
+	auth              transport.AuthMethod
+	mutex             *sync.Mutex
+}
+
+// NewGoGit initializes a go-git based ChannelSource. It behaves like
+// NewGit but never shells out to the git binary, so hosts without git
+// installed can still serve channels. sshKnownHostsFile, if set, is used
+// to verify the remote host key; if empty, "~/.ssh/known_hosts" is used.
+// There is no way to opt out of host key verification here.
+func NewGoGit(workdir, repositoryURL, sshPrivateKeyFile, sshKnownHostsFile string) (ConfigSource, error) {
+	absWorkdir, err := filepath.Abs(workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	repoName, err := getRepoName(repositoryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth transport.AuthMethod
+	if sshPrivateKeyFile != "" {
+		keys, err := gitssh.NewPublicKeysFromFile("git", sshPrivateKeyFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ssh private key %s: %w", sshPrivateKeyFile, err)
+		}
+
+		hostKeyCallback, err := loadKnownHosts(sshKnownHostsFile)
+		if err != nil {
+			return nil, err
+		}
+		keys.HostKeyCallbackHelper = gitssh.HostKeyCallbackHelper{
+			HostKeyCallback: hostKeyCallback,
+		}
+
+		auth = keys
+	}
+
+	return &GoGit{
+		workdir:           absWorkdir,
+		repoName:          repoName,
+		repositoryURL:     repositoryURL,
+		repoDir:           path.Join(absWorkdir, repoName),
+		sshPrivateKeyFile: sshPrivateKeyFile,
+		sshKnownHostsFile: sshKnownHostsFile,
+		auth:              auth,
+		mutex:             &sync.Mutex{},
+	}, nil
+}
+
+// loadKnownHosts builds a host key callback that verifies the remote
+// against knownHostsFile ("~/.ssh/known_hosts" if empty), so the go-git
+// backend can't silently fall back to ssh.InsecureIgnoreHostKey() the way
+// a nil HostKeyCallback would.
+func loadKnownHosts(knownHostsFile string) (ssh.HostKeyCallback, error) {
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("no known_hosts file configured and failed to determine home directory: %w", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts file %s: %w", knownHostsFile, err)
+	}
+
+	return callback, nil
+}
+
+// Get checks out the specified channel from the mirror repo into a fresh
+// worktree directory and returns its path and resolved revision. ctx
+// cancels the underlying go-git operations, e.g. on shutdown.
+func (g *GoGit) Get(ctx context.Context, channel string) (*Config, error) {
+	repoDir, version, err := g.worktreeCheckout(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		Version: version,
+		Path:    repoDir,
+	}, nil
+}
+
+// Delete removes the worktree checkout specified by the config Path.
+func (g *GoGit) Delete(ctx context.Context, config *Config) error {
+	return os.RemoveAll(config.Path)
+}
+
+// Update fetches the latest refs into the shared bare mirror repository,
+// cloning it first if it doesn't exist yet. ctx cancels the underlying
+// go-git operations, e.g. on shutdown.
+func (g *GoGit) Update(ctx context.Context) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	repo, err := git.PlainOpen(g.repoDir)
+	if err != nil {
+		if err != git.ErrRepositoryNotExists {
+			return err
+		}
+
+		repo, err = git.PlainCloneContext(ctx, g.repoDir, true, &git.CloneOptions{
+			URL:    g.repositoryURL,
+			Auth:   g.auth,
+			Mirror: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return err
+	}
+
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		Auth:  g.auth,
+		Force: true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	return pruneDeletedRefs(repo, remote, g.auth)
+}
+
+// pruneDeletedRefs removes branch and tag refs from repo that no longer
+// exist on remote. FetchOptions.Prune does this automatically on newer
+// go-git releases, but isn't available in every version CLM might be
+// built against, so Update does it itself instead of depending on the
+// field being present.
+func pruneDeletedRefs(repo *git.Repository, remote *git.Remote, auth transport.AuthMethod) error {
+	upstreamRefs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return err
+	}
+
+	upstream := make(map[plumbing.ReferenceName]bool, len(upstreamRefs))
+	for _, ref := range upstreamRefs {
+		upstream[ref.Name()] = true
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return err
+	}
+	defer refs.Close()
+
+	var stale []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if (name.IsBranch() || name.IsTag()) && !upstream[name] {
+			stale = append(stale, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range stale {
+		if err := repo.Storer.RemoveReference(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// worktreeCheckout clones the shared bare mirror repo into a timestamped
+// directory and checks out channel, mirroring the layout localClone uses
+// for the shell-based Git so callers can't tell the two backends apart.
+func (g *GoGit) worktreeCheckout(ctx context.Context, channel string) (string, string, error) {
+	repoDir := path.Join(g.workdir, fmt.Sprintf("%s_%s_%d", g.repoName, channel, time.Now().UTC().UnixNano()))
+
+	repo, err := git.PlainCloneContext(ctx, repoDir, false, &git.CloneOptions{
+		URL: g.repoDir,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", "", err
+	}
+
+	checkoutErr := wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(channel),
+	})
+	if checkoutErr != nil {
+		// channel isn't a local branch: a plain (non-mirror) clone only
+		// creates a refs/heads/* branch for the upstream's default
+		// branch, every other branch only exists as a refs/remotes/origin/*
+		// ref. Try that before falling back to resolving channel as a tag
+		// or raw revision, the same fallback `git checkout` gives us for
+		// free.
+		if ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", channel), true); err == nil {
+			checkoutErr = wt.Checkout(&git.CheckoutOptions{Hash: ref.Hash()})
+		}
+	}
+	if checkoutErr != nil {
+		hash, resolveErr := repo.ResolveRevision(plumbing.Revision(channel))
+		if resolveErr != nil {
+			return "", "", checkoutErr
+		}
+
+		if err := wt.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+			return "", "", err
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", "", err
+	}
+
+	return repoDir, head.Hash().String(), nil
+}
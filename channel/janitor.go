@@ -0,0 +1,173 @@
+package channel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	janitorOrphansFound = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "clm",
+		Subsystem: "channel",
+		Name:      "janitor_orphans_found_total",
+		Help:      "Number of abandoned channel checkouts found by the janitor.",
+	})
+	janitorOrphansRemoved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "clm",
+		Subsystem: "channel",
+		Name:      "janitor_orphans_removed_total",
+		Help:      "Number of abandoned channel checkouts removed by the janitor.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(janitorOrphansFound, janitorOrphansRemoved)
+}
+
+// JanitorState reports the health of the background garbage collector
+// started by StartJanitor, so the controller can surface disk-leak
+// conditions instead of silently filling the workdir over long runs.
+type JanitorState struct {
+	Running        bool
+	LastRun        time.Time
+	OrphansFound   int
+	OrphansRemoved int
+}
+
+// StartJanitor starts a goroutine that periodically scans g.workdir for
+// checkout directories abandoned by callers that crashed before calling
+// Delete, and removes any older than maxAge. It stops once ctx is done.
+func (g *Git) StartJanitor(ctx context.Context, interval, maxAge time.Duration) {
+	g.mutex.Lock()
+	g.janitorState.Running = true
+	g.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		defer func() {
+			g.mutex.Lock()
+			g.janitorState.Running = false
+			g.mutex.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.collectOrphans(maxAge)
+			}
+		}
+	}()
+}
+
+// JanitorState returns the current state of the background janitor
+// started by StartJanitor.
+func (g *Git) JanitorState() JanitorState {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.janitorState
+}
+
+// collectOrphans removes checkout directories in g.workdir that are older
+// than maxAge and aren't tracked in g.activePaths, i.e. checkouts whose
+// caller crashed before calling Delete.
+func (g *Git) collectOrphans(maxAge time.Duration) {
+	entries, err := os.ReadDir(g.workdir)
+	if err != nil {
+		log.Errorf("janitor: failed to list workdir %s: %v", g.workdir, err)
+		return
+	}
+
+	g.mutex.Lock()
+	active := make(map[string]bool, len(g.activePaths))
+	for p := range g.activePaths {
+		active[p] = true
+	}
+	g.mutex.Unlock()
+
+	now := time.Now()
+	found, removed := 0, 0
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		createdAt, ok := g.parseCheckoutDirName(entry.Name())
+		if !ok {
+			continue
+		}
+
+		fullPath := filepath.Join(g.workdir, entry.Name())
+		if active[fullPath] {
+			continue
+		}
+
+		age := now.Sub(createdAt)
+		if age < maxAge {
+			continue
+		}
+
+		found++
+		log.Warnf("janitor: found orphaned checkout %s (age %s)", fullPath, age)
+
+		if err := os.RemoveAll(fullPath); err != nil {
+			log.Errorf("janitor: failed to remove orphaned checkout %s: %v", fullPath, err)
+			continue
+		}
+
+		removed++
+	}
+
+	if found > 0 {
+		janitorOrphansFound.Add(float64(found))
+	}
+	if removed > 0 {
+		janitorOrphansRemoved.Add(float64(removed))
+	}
+
+	g.mutex.Lock()
+	g.janitorState.LastRun = now
+	g.janitorState.OrphansFound += found
+	g.janitorState.OrphansRemoved += removed
+	g.mutex.Unlock()
+}
+
+// parseCheckoutDirName recognizes the "<repoName>_<channel>_<unixnano>"
+// directories localClone creates and returns the unixnano suffix as a
+// time. It strips g.repoName as a known literal prefix and the trailing
+// digits as a known literal suffix, rather than matching the channel in
+// between with a generic pattern, so a channel name that itself contains
+// an underscore (e.g. a "feature_x" branch) is still recognized.
+func (g *Git) parseCheckoutDirName(name string) (time.Time, bool) {
+	prefix := g.repoName + "_"
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+
+	rest := strings.TrimPrefix(name, prefix)
+
+	idx := strings.LastIndex(rest, "_")
+	if idx < 0 {
+		return time.Time{}, false
+	}
+
+	suffix := rest[idx+1:]
+
+	createdNano, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, createdNano), true
+}
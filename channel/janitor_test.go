@@ -0,0 +1,58 @@
+package channel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCheckoutDirName(t *testing.T) {
+	g := &Git{repoName: "myrepo"}
+
+	for _, tc := range []struct {
+		name    string
+		dirName string
+		wantOK  bool
+		wantAt  int64
+	}{
+		{
+			name:    "simple channel",
+			dirName: "myrepo_stable_1625097600000000000",
+			wantOK:  true,
+			wantAt:  1625097600000000000,
+		},
+		{
+			name:    "channel name containing an underscore",
+			dirName: "myrepo_feature_x_1625097600000000000",
+			wantOK:  true,
+			wantAt:  1625097600000000000,
+		},
+		{
+			name:    "unrelated repo",
+			dirName: "otherrepo_stable_1625097600000000000",
+			wantOK:  false,
+		},
+		{
+			name:    "missing timestamp suffix",
+			dirName: "myrepo_stable",
+			wantOK:  false,
+		},
+		{
+			name:    "non-numeric suffix",
+			dirName: "myrepo_stable_notanumber",
+			wantOK:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			createdAt, ok := g.parseCheckoutDirName(tc.dirName)
+			if ok != tc.wantOK {
+				t.Fatalf("parseCheckoutDirName(%q) ok = %v, want %v", tc.dirName, ok, tc.wantOK)
+			}
+			if !tc.wantOK {
+				return
+			}
+			if want := time.Unix(0, tc.wantAt); !createdAt.Equal(want) {
+				t.Fatalf("parseCheckoutDirName(%q) = %v, want %v", tc.dirName, createdAt, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// MirrorTarget is an additional destination channel.Git.Update replicates
+// the canonical mirror repo to after every successful fetch, so a single
+// CLM instance can keep the channel repo replicated to backup git hosts.
+type MirrorTarget struct {
+	URL        string
+	SSHKeyFile string
+	// RefSpec, if set, is pushed with `git push --prune --force` instead
+	// of doing a full `git push --mirror`, e.g. to replicate only a
+	// subset of refs to a destination that isn't fully trusted.
+	RefSpec string
+}
+
+// MirrorPushStatus reports the outcome of the most recent push to a
+// single mirror target.
+type MirrorPushStatus struct {
+	URL         string
+	LastSuccess time.Time
+	LastError   string
+	BytesPushed int64
+}
+
+var (
+	mirrorPushTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "clm",
+		Subsystem: "channel",
+		Name:      "mirror_push_total",
+		Help:      "Number of channel mirror push attempts, by destination and outcome.",
+	}, []string{"url", "outcome"})
+	mirrorPushBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "clm",
+		Subsystem: "channel",
+		Name:      "mirror_push_bytes_total",
+		Help:      "Bytes pushed to each channel mirror destination.",
+	}, []string{"url"})
+)
+
+func init() {
+	prometheus.MustRegister(mirrorPushTotal, mirrorPushBytes)
+}
+
+// pushSizeRE extracts the transferred size git push --progress reports on
+// success, e.g. "Writing objects: 100% (5/5), 293 bytes | 97.00 KiB/s,
+// done." (small transfers are reported in plain "bytes", not "B").
+var pushSizeRE = regexp.MustCompile(`Writing objects:.*,\s*([\d.]+)\s*(bytes|KiB|MiB|GiB)`)
+
+// AddMirror registers an additional push destination. Update pushes the
+// mirror repo to it after every successful fetch from the upstream
+// channel repo; a failure pushing to one mirror doesn't affect any
+// other.
+func (g *Git) AddMirror(target MirrorTarget) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.mirrors = append(g.mirrors, target)
+}
+
+// MirrorStatus returns the current push status for every configured
+// mirror target, keyed by URL, so operators can tell a single failing
+// destination apart from a working replication setup.
+func (g *Git) MirrorStatus() map[string]MirrorPushStatus {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	status := make(map[string]MirrorPushStatus, len(g.mirrorStatus))
+	for url, s := range g.mirrorStatus {
+		status[url] = s
+	}
+	return status
+}
+
+// pushMirrors pushes the mirror repo to every configured MirrorTarget.
+// Each push is independent: one destination failing doesn't stop the
+// others from being attempted.
+func (g *Git) pushMirrors(ctx context.Context) {
+	g.mutex.Lock()
+	mirrors := make([]MirrorTarget, len(g.mirrors))
+	copy(mirrors, g.mirrors)
+	g.mutex.Unlock()
+
+	for _, mirror := range mirrors {
+		g.pushMirror(ctx, mirror)
+	}
+}
+
+func (g *Git) pushMirror(ctx context.Context, mirror MirrorTarget) {
+	// --progress forces git to emit the "Writing objects" line this code
+	// parses for BytesPushed even though stderr here isn't a TTY.
+	args := []string{"--git-dir", g.repoDir, "push", "--progress"}
+	if mirror.RefSpec != "" {
+		args = append(args, "--prune", "--force", mirror.URL, mirror.RefSpec)
+	} else {
+		args = append(args, "--mirror", mirror.URL)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if mirror.SSHKeyFile != "" {
+		// Deliberately does not set StrictHostKeyChecking=no: the
+		// destination host key must already be in the known_hosts of
+		// the user running CLM, same as any other git remote.
+		cmd.Env = []string{fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s", mirror.SSHKeyFile)}
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	status := g.mirrorStatus[mirror.URL]
+	status.URL = mirror.URL
+
+	if err != nil {
+		status.LastError = fmt.Sprintf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		g.mirrorStatus[mirror.URL] = status
+		mirrorPushTotal.WithLabelValues(mirror.URL, "failure").Inc()
+		log.Errorf("failed to push channel mirror %s: %v", mirror.URL, err)
+		return
+	}
+
+	bytesPushed := parsePushSize(stderr.String())
+
+	status.LastError = ""
+	status.LastSuccess = time.Now()
+	status.BytesPushed = bytesPushed
+	g.mirrorStatus[mirror.URL] = status
+
+	mirrorPushTotal.WithLabelValues(mirror.URL, "success").Inc()
+	mirrorPushBytes.WithLabelValues(mirror.URL).Add(float64(bytesPushed))
+}
+
+// parsePushSize extracts the number of bytes git push reports having
+// transferred, returning 0 if the output doesn't contain the expected
+// "Writing objects" progress line (e.g. when there was nothing to push).
+func parsePushSize(output string) int64 {
+	match := pushSizeRE.FindStringSubmatch(output)
+	if match == nil {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	switch match[2] {
+	case "KiB":
+		value *= 1024
+	case "MiB":
+		value *= 1024 * 1024
+	case "GiB":
+		value *= 1024 * 1024 * 1024
+	}
+
+	return int64(value)
+}
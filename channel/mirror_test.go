@@ -0,0 +1,38 @@
+package channel
+
+import "testing"
+
+func TestParsePushSize(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   int64
+	}{
+		{
+			name:   "small push reported in plain bytes",
+			output: "Writing objects: 100% (5/5), 293 bytes | 97.00 KiB/s, done.\n",
+			want:   293,
+		},
+		{
+			name:   "larger push reported in KiB",
+			output: "Writing objects: 100% (12/12), 3.45 KiB | 3.45 MiB/s, done.\n",
+			want:   3532, // 3.45 * 1024, truncated
+		},
+		{
+			name:   "push reported in MiB",
+			output: "Writing objects: 100% (900/900), 2.00 MiB | 5.00 MiB/s, done.\n",
+			want:   2 * 1024 * 1024,
+		},
+		{
+			name:   "no progress line (e.g. --progress omitted, or nothing to push)",
+			output: "Everything up-to-date\n",
+			want:   0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parsePushSize(tc.output); got != tc.want {
+				t.Fatalf("parsePushSize(%q) = %d, want %d", tc.output, got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"context"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -16,10 +18,34 @@ const (
 	updatePriorityAlreadyUpdating
 )
 
+const (
+	// backoffBase is the unit of the backoff delay: after n consecutive
+	// failures a cluster waits backoffBase * 2^n (so 2*backoffBase after
+	// the first failure), before jitter.
+	backoffBase = 30 * time.Second
+	// backoffCap bounds the exponent so the delay doesn't grow forever.
+	backoffCap = 6 // backoffBase * 2^6 = 32m
+	// backoffJitterFraction adds up to this fraction of the computed
+	// delay as jitter, so clusters that failed together don't retry in
+	// lockstep.
+	backoffJitterFraction = 0.2
+)
+
+// ClusterConditions reports why a cluster is or isn't currently eligible
+// for processing, modeled on Kubernetes-style condition fields so an HTTP
+// status handler can render "why isn't cluster X being picked up".
+type ClusterConditions struct {
+	LastUpdateSucceeded bool
+	LastUpdateReason    string
+	ConsecutiveFailures int
+	NextEligibleAt      time.Time
+}
+
 type clusterInfo struct {
 	lastProcessed time.Time
 	processing    bool
 	cluster       *api.Cluster
+	conditions    ClusterConditions
 }
 
 // ClusterList maintains the state of all active clusters
@@ -27,6 +53,8 @@ type ClusterList struct {
 	sync.Mutex
 	accountFilter config.IncludeExcludeFilter
 	clusters      map[string]*clusterInfo
+	processingWg  sync.WaitGroup
+	draining      bool
 }
 
 func NewClusterList(accountFilter config.IncludeExcludeFilter) *ClusterList {
@@ -97,19 +125,30 @@ func updatePriority(cluster *api.Cluster) uint32 {
 
 // SelectNext returns the next cluster of update, if any, and marks it as being processed. A cluster with higher
 // priority will be selected first, in case of ties it'll select a cluster that hasn't been updated for the longest
-// time.
-func (clusterList *ClusterList) SelectNext() *api.Cluster {
+// time. Once ctx is done or Shutdown has been called, SelectNext stops handing out new clusters so in-flight work
+// can be drained via Drain.
+func (clusterList *ClusterList) SelectNext(ctx context.Context) *api.Cluster {
 	clusterList.Lock()
 	defer clusterList.Unlock()
 
+	if ctx.Err() != nil || clusterList.draining {
+		return nil
+	}
+
 	var nextCluster *clusterInfo
 	var nextClusterPriority uint32
 
+	now := time.Now()
+
 	for _, cluster := range clusterList.clusters {
 		if cluster.processing {
 			continue
 		}
 
+		if now.Before(cluster.conditions.NextEligibleAt) {
+			continue
+		}
+
 		if nextCluster == nil {
 			nextCluster = cluster
 			nextClusterPriority = updatePriority(cluster.cluster)
@@ -128,16 +167,110 @@ func (clusterList *ClusterList) SelectNext() *api.Cluster {
 	}
 
 	nextCluster.processing = true
+	clusterList.processingWg.Add(1)
 	return nextCluster.cluster
 }
 
-// ClusterProcessed marks a cluster as no longer being processed.
-func (clusterList *ClusterList) ClusterProcessed(id string) {
+// ClusterProcessed marks a cluster as no longer being processed and
+// records the outcome. On failure the cluster is backed off
+// exponentially, so a cluster that keeps failing (e.g. stuck with
+// updatePriorityAlreadyUpdating) doesn't get re-selected in a tight loop;
+// on success the backoff is reset.
+func (clusterList *ClusterList) ClusterProcessed(id string, err error) {
+	clusterList.Lock()
+	defer clusterList.Unlock()
+
+	cluster, ok := clusterList.clusters[id]
+	if !ok {
+		return
+	}
+
+	defer clusterList.processingWg.Done()
+
+	cluster.processing = false
+	cluster.lastProcessed = time.Now()
+
+	if err != nil {
+		cluster.conditions.LastUpdateSucceeded = false
+		cluster.conditions.LastUpdateReason = err.Error()
+		cluster.conditions.ConsecutiveFailures++
+		cluster.conditions.NextEligibleAt = cluster.lastProcessed.Add(backoffDuration(cluster.conditions.ConsecutiveFailures))
+	} else {
+		cluster.conditions.LastUpdateSucceeded = true
+		cluster.conditions.LastUpdateReason = ""
+		cluster.conditions.ConsecutiveFailures = 0
+		cluster.conditions.NextEligibleAt = time.Time{}
+	}
+}
+
+// ClusterConditions returns the current conditions for cluster id, so
+// callers (e.g. an HTTP status handler) can explain why a cluster is or
+// isn't being picked up for an update.
+func (clusterList *ClusterList) ClusterConditions(id string) (ClusterConditions, bool) {
 	clusterList.Lock()
 	defer clusterList.Unlock()
 
-	if cluster, ok := clusterList.clusters[id]; ok {
-		cluster.processing = false
-		cluster.lastProcessed = time.Now()
+	cluster, ok := clusterList.clusters[id]
+	if !ok {
+		return ClusterConditions{}, false
 	}
+
+	return cluster.conditions, true
+}
+
+// Shutdown marks the ClusterList as draining: SelectNext will stop handing
+// out new clusters, but clusters already being processed are left alone
+// so callers can wait for them via Drain.
+func (clusterList *ClusterList) Shutdown() {
+	clusterList.Lock()
+	defer clusterList.Unlock()
+
+	clusterList.draining = true
+}
+
+// Draining reports whether Shutdown has been called, so callers can tell
+// "idle because drained" apart from "idle because nothing to do".
+func (clusterList *ClusterList) Draining() bool {
+	clusterList.Lock()
+	defer clusterList.Unlock()
+
+	return clusterList.draining
+}
+
+// Drain calls Shutdown and then blocks until every cluster that was being
+// processed finishes, or ctx is done, whichever happens first. This lets
+// the controller's main loop wait for in-flight updates to complete
+// before exiting, instead of leaving a half-updated cluster behind when
+// CLM is redeployed.
+func (clusterList *ClusterList) Drain(ctx context.Context) error {
+	clusterList.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		clusterList.processingWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoffDuration returns the exponential backoff delay for a cluster
+// that has failed `failures` times in a row, capped at backoffCap
+// doublings and jittered so clusters that failed together don't retry in
+// lockstep.
+func backoffDuration(failures int) time.Duration {
+	exp := failures
+	if exp > backoffCap {
+		exp = backoffCap
+	}
+
+	delay := backoffBase * time.Duration(int64(1)<<uint(exp))
+	jitter := time.Duration(rand.Int63n(int64(float64(delay) * backoffJitterFraction)))
+
+	return delay + jitter
 }
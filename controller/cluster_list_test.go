@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zalando-incubator/cluster-lifecycle-manager/api"
+)
+
+func TestSelectNextSkipsClusterInBackoff(t *testing.T) {
+	cl := &ClusterList{clusters: map[string]*clusterInfo{
+		"future": {
+			cluster:    &api.Cluster{ID: "future"},
+			conditions: ClusterConditions{NextEligibleAt: time.Now().Add(time.Hour)},
+		},
+		"eligible": {
+			cluster: &api.Cluster{ID: "eligible"},
+		},
+	}}
+
+	got := cl.SelectNext(context.Background())
+	if got == nil || got.ID != "eligible" {
+		t.Fatalf("expected eligible cluster to be selected, got %+v", got)
+	}
+}
+
+func TestSelectNextReturnsNilWhenContextDone(t *testing.T) {
+	cl := &ClusterList{clusters: map[string]*clusterInfo{
+		"a": {cluster: &api.Cluster{ID: "a"}},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if got := cl.SelectNext(ctx); got != nil {
+		t.Fatalf("expected no cluster selected once ctx is done, got %+v", got)
+	}
+}
+
+func TestClusterProcessedTransitions(t *testing.T) {
+	cl := &ClusterList{clusters: map[string]*clusterInfo{
+		"a": {cluster: &api.Cluster{ID: "a"}, processing: true},
+	}}
+	cl.processingWg.Add(1)
+
+	cl.ClusterProcessed("a", errors.New("boom"))
+
+	cond, ok := cl.ClusterConditions("a")
+	if !ok {
+		t.Fatalf("expected conditions for cluster a")
+	}
+	if cond.LastUpdateSucceeded {
+		t.Fatalf("expected LastUpdateSucceeded=false after a failure")
+	}
+	if cond.ConsecutiveFailures != 1 {
+		t.Fatalf("expected ConsecutiveFailures=1, got %d", cond.ConsecutiveFailures)
+	}
+	if !cond.NextEligibleAt.After(time.Now()) {
+		t.Fatalf("expected NextEligibleAt to be in the future after a failure")
+	}
+
+	cl.processingWg.Add(1)
+	cl.ClusterProcessed("a", nil)
+
+	cond, _ = cl.ClusterConditions("a")
+	if !cond.LastUpdateSucceeded {
+		t.Fatalf("expected LastUpdateSucceeded=true after success")
+	}
+	if cond.ConsecutiveFailures != 0 {
+		t.Fatalf("expected ConsecutiveFailures reset to 0 after success, got %d", cond.ConsecutiveFailures)
+	}
+	if !cond.NextEligibleAt.IsZero() {
+		t.Fatalf("expected NextEligibleAt reset after success")
+	}
+}
+
+func TestDrainWaitsForProcessingCluster(t *testing.T) {
+	cl := &ClusterList{clusters: map[string]*clusterInfo{
+		"a": {cluster: &api.Cluster{ID: "a"}},
+	}}
+
+	if got := cl.SelectNext(context.Background()); got == nil || got.ID != "a" {
+		t.Fatalf("expected to select cluster a, got %+v", got)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cl.Drain(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Drain returned before the in-flight cluster finished processing")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !cl.Draining() {
+		t.Fatalf("expected ClusterList to report draining once Drain is called")
+	}
+
+	cl.ClusterProcessed("a", nil)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Drain returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Drain did not return after the cluster finished processing")
+	}
+}
+
+func TestDrainReturnsContextErrorWhenCancelledFirst(t *testing.T) {
+	cl := &ClusterList{clusters: map[string]*clusterInfo{
+		"a": {cluster: &api.Cluster{ID: "a"}},
+	}}
+
+	cl.SelectNext(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := cl.Drain(ctx); err == nil {
+		t.Fatalf("expected Drain to return an error when ctx is already cancelled")
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		failures int
+		wantMin  int64 // nanoseconds, backoffBase*2^exp
+		wantMax  int64 // wantMin plus the maximum possible jitter
+	}{
+		{
+			name:     "first failure",
+			failures: 1,
+			wantMin:  int64(backoffBase * 2),
+			wantMax:  int64(float64(backoffBase*2) * (1 + backoffJitterFraction)),
+		},
+		{
+			name:     "several failures",
+			failures: 3,
+			wantMin:  int64(backoffBase * 8),
+			wantMax:  int64(float64(backoffBase*8) * (1 + backoffJitterFraction)),
+		},
+		{
+			name:     "failures beyond the cap don't keep doubling",
+			failures: backoffCap + 10,
+			wantMin:  int64(backoffBase) << backoffCap,
+			wantMax:  int64(float64(int64(backoffBase)<<backoffCap) * (1 + backoffJitterFraction)),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := int64(backoffDuration(tc.failures))
+				if got < tc.wantMin || got > tc.wantMax {
+					t.Fatalf("backoffDuration(%d) = %d, want within [%d, %d]", tc.failures, got, tc.wantMin, tc.wantMax)
+				}
+			}
+		})
+	}
+}